@@ -0,0 +1,52 @@
+package consistenthash
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentAddRemoveGet exercises Get/Get2 racing against Add/Remove on
+// both ring and rendezvous Maps; run with -race to verify the RCU-style
+// atomic ring swap holds up under concurrent writers and readers.
+func TestConcurrentAddRemoveGet(t *testing.T) {
+	for _, newMap := range []func() *Map{
+		func() *Map { return New(50, nil) },
+		func() *Map { return NewRendezvous(nil) },
+	} {
+		hash := newMap()
+		hash.Add("seed-a", "seed-b", "seed-c")
+
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(n int) {
+				defer wg.Done()
+				for j := 0; ; j++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					key := fmt.Sprintf("key-%d-%d", n, j)
+					hash.Get(key)
+					hash.Get2(key)
+					hash.GetWithLoad(key, func(string) int64 { return 0 }, 1000)
+					hash.Distribution()
+					hash.Imbalance()
+				}
+			}(i)
+		}
+
+		for i := 0; i < 20; i++ {
+			node := fmt.Sprintf("node-%d", i)
+			hash.Add(node)
+			hash.Remove(node)
+		}
+
+		close(stop)
+		wg.Wait()
+	}
+}
@@ -18,20 +18,39 @@ limitations under the License.
 package consistenthash
 
 import (
-	"hash/crc32"
+	"math"
 	"sort"
 	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/davies/groupcache/consistenthash/hashers"
 )
 
 type Hash func(data []byte) uint32
 
-type Map struct {
-	hash     Hash
-	replica  int
-	keys     []int // Sorted
+// ringState is the immutable snapshot published to readers. Get, Get2,
+// lookup, IsEmpty, and the metrics accessors all work from one ringState
+// loaded once, so they never block on or race with a concurrent Add/Remove.
+type ringState struct {
+	keys     []int // Sorted; empty in rendezvous mode
 	hashMap  map[int]string
-	hashs    map[string][]int
-	replicas map[string]int
+	replicas map[string]int // weight per node; authoritative for rendezvous and IsEmpty
+}
+
+type Map struct {
+	hash       Hash
+	replica    int
+	rendezvous bool
+
+	// mu serializes writers (Add*/Remove/SetObserver) and Snapshot. The
+	// lookup and metrics readers never take it; they load state instead.
+	mu       sync.Mutex
+	replicas map[string]int   // requested weights, mu-protected
+	hashs    map[string][]int // per-key vnode hash cache, mu-protected
+	observer func(RingEvent)  // mu-protected
+
+	state atomic.Pointer[ringState]
 }
 
 func New(replicas int, fn Hash) *Map {
@@ -40,17 +59,46 @@ func New(replicas int, fn Hash) *Map {
 		hash:     fn,
 		replicas: make(map[string]int),
 		hashs:    make(map[string][]int),
-		hashMap:  make(map[int]string),
 	}
 	if m.hash == nil {
-		m.hash = crc32.ChecksumIEEE
+		m.hash = hashers.XXHash
 	}
+	m.state.Store(&ringState{hashMap: make(map[int]string), replicas: make(map[string]int)})
 	return m
 }
 
+// NewSeeded creates a Map like New, but hashes with an xxHash instance
+// seeded by seed instead of the unseeded default. This lets operators vary
+// key placement across peers or processes, e.g. to defeat adversarial key
+// selection on public-facing groupcache instances.
+func NewSeeded(seed uint64, replicas int) *Map {
+	return New(replicas, hashers.XXHashSeeded(seed))
+}
+
+// NewRendezvous creates a Map that uses Rendezvous (Highest Random Weight)
+// hashing instead of ring hashing. Unlike the ring, it needs no replicas and
+// no adjust() pass: every Get/Get2 scores all registered nodes and picks the
+// winner(s), giving exact expected balance and a Remove that remaps only the
+// removed node's keys, at the cost of an O(nodes) lookup.
+func NewRendezvous(fn Hash) *Map {
+	m := New(1, fn)
+	m.rendezvous = true
+	return m
+}
+
+// copyWeights returns an independent copy of a weight map, so a ringState
+// published to readers is never aliased to the writer-owned m.replicas.
+func copyWeights(w map[string]int) map[string]int {
+	c := make(map[string]int, len(w))
+	for k, v := range w {
+		c[k] = v
+	}
+	return c
+}
+
 // Returns true if there are no items available.
 func (m *Map) IsEmpty() bool {
-	return len(m.replicas) == 0
+	return len(m.state.Load().replicas) == 0
 }
 
 // Adds some keys to the hash.
@@ -60,36 +108,67 @@ func (m *Map) Add(keys ...string) {
 	}
 }
 
-// Adds a key with different replica to the hash.
+// Adds a key with different replica to the hash. In rendezvous mode,
+// replica is instead the node's weight in the HRW score.
 func (m *Map) AddWithWeight(key string, replica int) {
 	if replica < 1 {
 		panic("replica should be positive")
 	}
-	old := m.replicas[key]
-	if old != replica {
-		m.keys = m.keys[:0]
-		m.replicas[key] = replica
-		m.adjust(5, 0.75)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if old, ok := m.replicas[key]; ok && old == replica {
+		return
+	}
+	before := m.observedDistribution(m.state.Load())
+	m.replicas[key] = replica
+
+	if m.rendezvous {
+		next := &ringState{replicas: copyWeights(m.replicas)}
+		m.state.Store(next)
+		m.notify(RingEventAdd, key, before, m.observedDistribution(next))
+		return
 	}
+
+	next := m.adjust(5, 0.75)
+	m.state.Store(next)
+	m.notify(RingEventAdd, key, before, m.observedDistribution(next))
 }
 
 // Remove a key from hash
 func (m *Map) Remove(key string) {
-	if _, ok := m.replicas[key]; ok {
-		delete(m.replicas, key)
-		delete(m.hashs, key)
-		m.keys = m.keys[:0]
-		m.adjust(5, 0.75)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.replicas[key]; !ok {
+		return
 	}
+	before := m.observedDistribution(m.state.Load())
+	delete(m.replicas, key)
+
+	if m.rendezvous {
+		next := &ringState{replicas: copyWeights(m.replicas)}
+		m.state.Store(next)
+		m.notify(RingEventRemove, key, before, m.observedDistribution(next))
+		return
+	}
+
+	delete(m.hashs, key)
+	next := m.adjust(5, 0.75)
+	m.state.Store(next)
+	m.notify(RingEventRemove, key, before, m.observedDistribution(next))
 }
 
-func (m *Map) calc(replicas map[string]int) {
+// calcRing builds the sorted vnode ring for vnodeCounts, growing the
+// per-key hash cache in m.hashs as needed. Called with mu held.
+func (m *Map) calcRing(vnodeCounts map[string]int) ([]int, map[int]string) {
 	var total int
-	for _, r := range replicas {
+	for _, r := range vnodeCounts {
 		total += r
 	}
-	m.keys = m.keys[:0]
-	for key, r := range replicas {
+	keys := make([]int, 0, total)
+	hashMap := make(map[int]string, total)
+	for key, r := range vnodeCounts {
 		hs := m.hashs[key]
 		for i := 0; i < r; i++ {
 			var hash int
@@ -99,41 +178,42 @@ func (m *Map) calc(replicas map[string]int) {
 				hash = int(m.hash([]byte(strconv.Itoa(i) + key)))
 				hs = append(hs, hash)
 			}
-			m.keys = append(m.keys, hash)
-			m.hashMap[hash] = key
+			keys = append(keys, hash)
+			hashMap[hash] = key
 		}
 		m.hashs[key] = hs
 	}
-	sort.Ints(m.keys)
+	sort.Ints(keys)
+	return keys, hashMap
 }
 
-// adjust the replica for keys
-func (m *Map) adjust(tries int, scale float64) {
-	if len(m.keys) != 0 {
-		return
-	}
-	m.calc(m.replicas)
+// adjust builds a fresh ringState for the current m.replicas, nudging vnode
+// counts away from their requested weights to correct the arc-coverage
+// skew ring hashing produces for small node counts. Called with mu held; it
+// only builds ringStates, it never publishes one itself.
+func (m *Map) adjust(tries int, scale float64) *ringState {
+	weights := copyWeights(m.replicas)
+	keys, hashMap := m.calcRing(weights)
+	state := &ringState{keys: keys, hashMap: hashMap, replicas: weights}
 	if len(m.replicas) <= 1 || m.replica < 10 {
-		return
+		return state
 	}
-	var replicas int
+
+	var total int
 	reps := make(map[string]int, len(m.replicas))
 	for k, r := range m.replicas {
 		reps[k] = r
-		replicas += r
+		total += r
 	}
 
 	for t := 0; t < tries; t++ {
-		stat := make(map[string]int, len(m.replicas))
-		stat[m.hashMap[m.keys[0]]] = m.keys[0] + int(1<<32) - m.keys[len(m.keys)-1]
-		for i, h := range m.keys[1:] {
-			stat[m.hashMap[h]] += h - m.keys[i]
-		}
+		stat := arcCoverage(state)
+		before := m.observedDistribution(state)
 		var changed bool
 		for k, v := range stat {
 			actual := float64(v) / float64(1<<32)
 			rep := reps[k]
-			expect := float64(m.replicas[k]) / float64(replicas)
+			expect := float64(m.replicas[k]) / float64(total)
 			adjust := int(float64(rep) * (expect - actual) / float64(expect) * scale)
 			if adjust > 1 || adjust < 1 {
 				reps[k] += adjust
@@ -141,29 +221,122 @@ func (m *Map) adjust(tries int, scale float64) {
 			}
 		}
 		if !changed {
-			return
+			return state
+		}
+		keys, hashMap = m.calcRing(reps)
+		state = &ringState{keys: keys, hashMap: hashMap, replicas: weights}
+		if m.observer != nil {
+			m.observer(RingEvent{Type: RingEventAdjustIteration, Iteration: t, Before: before, After: m.observedDistribution(state)})
 		}
-		m.calc(reps)
 	}
+	return state
 }
 
 // Gets the closest item in the hash to the provided key.
 func (m *Map) Get(key string) string {
-	if m.IsEmpty() {
+	state := m.state.Load()
+	if len(state.replicas) == 0 {
+		return ""
+	}
+	if m.rendezvous {
+		first, _ := m.rendezvousGet(state, key)
+		return first
+	}
+	idx := lookup(state, m.hash, key)
+	return state.hashMap[state.keys[idx]]
+}
+
+// rendezvousScore computes the HRW score of node for key: the node with the
+// highest score wins, and a node with weight w wins with probability
+// proportional to w among equally-considered nodes.
+func (m *Map) rendezvousScore(node string, weight int, key string) float64 {
+	h := m.hash([]byte(node + key))
+	// Map the hash into the open interval (0, 1) so log() never sees 0.
+	u := (float64(h) + 1) / (float64(math.MaxUint32) + 2)
+	w := float64(weight)
+	if w <= 0 {
+		w = 1
+	}
+	return -w / math.Log(u)
+}
+
+// rendezvousGet returns the top-1 and top-2 scoring nodes for key.
+func (m *Map) rendezvousGet(state *ringState, key string) (string, string) {
+	var first, second string
+	var haveFirst, haveSecond bool
+	var firstScore, secondScore float64
+	for node, weight := range state.replicas {
+		score := m.rendezvousScore(node, weight, key)
+		if !haveFirst || score > firstScore {
+			second, secondScore, haveSecond = first, firstScore, haveFirst
+			first, firstScore, haveFirst = node, score, true
+		} else if !haveSecond || score > secondScore {
+			second, secondScore, haveSecond = node, score, true
+		}
+	}
+	return first, second
+}
+
+// GetWithLoad gets an item in the hash for the provided key, skipping nodes
+// whose load (as reported by the load callback) has already reached
+// capacity. This bounds every node's load to roughly capacity, which callers
+// should set to ceil((1+epsilon)*totalLoad/nodes) for their chosen epsilon.
+// It cycles at most once through the ring/node set; if every node is at
+// capacity it falls back to the node the plain lookup would have returned.
+func (m *Map) GetWithLoad(key string, load func(node string) int64, capacity int64) string {
+	state := m.state.Load()
+	if len(state.replicas) == 0 {
 		return ""
 	}
-	idx := m.lookup(key)
-	return m.hashMap[m.keys[idx]]
+	if m.rendezvous {
+		return m.rendezvousGetWithLoad(state, key, load, capacity)
+	}
+	start := lookup(state, m.hash, key)
+	firstNode := state.hashMap[state.keys[start]]
+	idx := start
+	for {
+		node := state.hashMap[state.keys[idx]]
+		if load(node) < capacity {
+			return node
+		}
+		idx++
+		if idx == len(state.keys) {
+			idx = 0
+		}
+		if idx == start {
+			return firstNode
+		}
+	}
 }
 
-func (m *Map) lookup(key string) int {
-	hash := int(m.hash([]byte(key)))
+// rendezvousGetWithLoad ranks nodes by HRW score, high to low, and returns
+// the first one under capacity, cycling once through all candidates.
+func (m *Map) rendezvousGetWithLoad(state *ringState, key string, load func(node string) int64, capacity int64) string {
+	type scored struct {
+		node  string
+		score float64
+	}
+	nodes := make([]scored, 0, len(state.replicas))
+	for node, weight := range state.replicas {
+		nodes = append(nodes, scored{node, m.rendezvousScore(node, weight, key)})
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].score > nodes[j].score })
+	for _, n := range nodes {
+		if load(n.node) < capacity {
+			return n.node
+		}
+	}
+	return nodes[0].node
+}
+
+func lookup(state *ringState, hash Hash, key string) int {
+	h := int(hash([]byte(key)))
 
 	// Binary search for appropriate replica.
-	idx := sort.Search(len(m.keys), func(i int) bool { return m.keys[i] >= hash })
+	idx := sort.Search(len(state.keys), func(i int) bool { return state.keys[i] >= h })
 
 	// Means we have cycled back to the first replica.
-	if idx == len(m.keys) {
+	if idx == len(state.keys) {
 		idx = 0
 	}
 	return idx
@@ -171,21 +344,25 @@ func (m *Map) lookup(key string) int {
 
 // Gets the two items in the hash to the provided key.
 func (m *Map) Get2(key string) (string, string) {
-	if m.IsEmpty() {
+	state := m.state.Load()
+	if len(state.replicas) == 0 {
 		return "", ""
 	}
-	idx := m.lookup(key)
-	first := m.hashMap[m.keys[idx]]
+	if m.rendezvous {
+		return m.rendezvousGet(state, key)
+	}
+	idx := lookup(state, m.hash, key)
+	first := state.hashMap[state.keys[idx]]
 	idx2 := idx
 	second := ""
-	if len(m.replicas) > 1 {
-		for m.hashMap[m.keys[idx2]] == first {
+	if len(state.replicas) > 1 {
+		for state.hashMap[state.keys[idx2]] == first {
 			idx2++
-			if idx2 == len(m.keys) {
+			if idx2 == len(state.keys) {
 				idx2 = 0
 			}
 		}
-		second = m.hashMap[m.keys[idx2]]
+		second = state.hashMap[state.keys[idx2]]
 	}
 	return first, second
 }
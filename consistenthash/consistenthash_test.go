@@ -18,10 +18,12 @@ package consistenthash
 
 import (
 	"fmt"
+	"hash/crc32"
 	"log"
 	"strconv"
 	"testing"
 
+	"github.com/davies/groupcache/consistenthash/hashers"
 	"github.com/spaolacci/murmur3"
 )
 
@@ -69,8 +71,8 @@ func TestHashing(t *testing.T) {
 }
 
 func TestConsistency(t *testing.T) {
-	hash1 := New(1, nil)
-	hash2 := New(1, nil)
+	hash1 := New(1, crc32.ChecksumIEEE)
+	hash2 := New(1, crc32.ChecksumIEEE)
 
 	hash1.Add("Bill", "Bob", "Bonny")
 	hash2.Add("Bob", "Bonny", "Bill")
@@ -138,6 +140,131 @@ func TestBalance(t *testing.T) {
 	}
 }
 
+func TestDefaultHashIsXXHash(t *testing.T) {
+	hash := New(3, nil)
+	hash.Add("a", "b", "c")
+	data := []byte("somekey")
+	if got, want := hash.hash(data), hashers.XXHash(data); got != want {
+		t.Errorf("default hash should be hashers.XXHash: got %d, want %d", got, want)
+	}
+	if hash.Get("somekey") == "" {
+		t.Errorf("expected a node back from the xxhash-backed default")
+	}
+}
+
+func TestNewSeededConsistency(t *testing.T) {
+	hash1 := NewSeeded(42, 10)
+	hash2 := NewSeeded(42, 10)
+
+	hash1.Add("a", "b", "c")
+	hash2.Add("a", "b", "c")
+
+	if hash1.Get("somekey") != hash2.Get("somekey") {
+		t.Errorf("two maps seeded the same should place keys the same")
+	}
+}
+
+func TestGetWithLoad(t *testing.T) {
+	hash := New(100, murmur3.Sum32)
+	hash.Add("a", "b", "c")
+
+	load := map[string]int64{"a": 0, "b": 0, "c": 0}
+	get := func(node string) int64 { return load[node] }
+
+	const capacity = 5
+	for i := 0; i < 3*capacity; i++ {
+		key := fmt.Sprintf("key%d", i)
+		node := hash.GetWithLoad(key, get, capacity)
+		load[node]++
+	}
+
+	for node, l := range load {
+		if l > capacity {
+			t.Errorf("node %s exceeded capacity: %d > %d", node, l, capacity)
+		}
+	}
+}
+
+func TestGetWithLoadAllNodesOverCapacity(t *testing.T) {
+	hash := New(100, murmur3.Sum32)
+	hash.Add("a", "b", "c")
+
+	full := func(string) int64 { return 1 }
+
+	key := "somekey"
+	want := hash.Get(key)
+	if got := hash.GetWithLoad(key, full, 1); got != want {
+		t.Errorf("GetWithLoad with every node over capacity should fall back to the plain lookup's node: got %q, want %q", got, want)
+	}
+}
+
+func TestRendezvousConsistency(t *testing.T) {
+	hash1 := NewRendezvous(nil)
+	hash2 := NewRendezvous(nil)
+
+	hash1.Add("Bill", "Bob", "Bonny")
+	hash2.Add("Bob", "Bonny", "Bill")
+
+	if hash1.Get("Ben") != hash2.Get("Ben") {
+		t.Errorf("Fetching 'Ben' from both hashes should be the same")
+	}
+}
+
+func TestRendezvousGet2(t *testing.T) {
+	hash := NewRendezvous(nil)
+	hash.Add("a", "b", "c")
+
+	first, second := hash.Get2("somekey")
+	if first == "" || second == "" || first == second {
+		t.Errorf("Get2 should return two distinct non-empty nodes, got %q and %q", first, second)
+	}
+	if hash.Get("somekey") != first {
+		t.Errorf("Get and Get2 should agree on the top node")
+	}
+}
+
+func TestRendezvousEmptyStringNodeName(t *testing.T) {
+	hash := NewRendezvous(murmur3.Sum32)
+	nodes := []string{"", "a", "b", "c", "d"}
+	hash.Add(nodes...)
+
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("key%d", i)
+
+		var want string
+		var wantScore float64
+		first := true
+		for _, node := range nodes {
+			score := hash.rendezvousScore(node, 1, key)
+			if first || score > wantScore {
+				want, wantScore, first = node, score, false
+			}
+		}
+
+		if got := hash.Get(key); got != want {
+			t.Errorf("Get(%q) = %q, want %q (the highest-scoring node, including the \"\" node)", key, got, want)
+		}
+	}
+}
+
+func TestRendezvousWeight(t *testing.T) {
+	hash := NewRendezvous(murmur3.Sum32)
+	hash.AddWithWeight("heavy", 9)
+	hash.AddWithWeight("light", 1)
+
+	N := 10000
+	var heavy int
+	for i := 0; i < N; i++ {
+		if hash.Get(fmt.Sprintf("key%d", i)) == "heavy" {
+			heavy++
+		}
+	}
+	// heavy carries 9x the weight of light, so it should win the large majority of keys.
+	if frac := float64(heavy) / float64(N); frac < 0.8 {
+		t.Errorf("expected heavy node to win most keys with weight 9 vs 1, got %.2f", frac)
+	}
+}
+
 func BenchmarkGet8(b *testing.B)   { benchmarkGet(b, 8) }
 func BenchmarkGet32(b *testing.B)  { benchmarkGet(b, 32) }
 func BenchmarkGet128(b *testing.B) { benchmarkGet(b, 128) }
@@ -0,0 +1,49 @@
+// Package hashers provides ready-made, benchmark-selected Hash
+// implementations for consistenthash.Map, replacing the package's
+// historical crc32.ChecksumIEEE default; hashers_test.go benchmarks Get
+// with each of them across the same shard counts.
+package hashers
+
+import (
+	"hash/maphash"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/spaolacci/murmur3"
+)
+
+// XXHash hashes with the 64-bit xxHash algorithm, truncated to 32 bits. It
+// is the fastest of this package's hashers and is consistenthash.New's
+// default when the caller passes a nil Hash.
+func XXHash(data []byte) uint32 {
+	return uint32(xxhash.Sum64(data))
+}
+
+// XXHashSeeded returns an XXHash variant seeded with seed, letting operators
+// vary key placement across peers or processes, e.g. to defeat adversarial
+// key selection on public-facing instances.
+func XXHashSeeded(seed uint64) func(data []byte) uint32 {
+	return func(data []byte) uint32 {
+		d := xxhash.NewWithSeed(seed)
+		_, _ = d.Write(data)
+		return uint32(d.Sum64())
+	}
+}
+
+// Murmur3 wraps murmur3.Sum32, the hash the package's own balance tests
+// already use.
+func Murmur3(data []byte) uint32 {
+	return murmur3.Sum32(data)
+}
+
+// mapHashSeed is generated once per process, so MapHash's placement varies
+// across restarts without callers having to manage a seed themselves.
+var mapHashSeed = maphash.MakeSeed()
+
+// MapHash hashes with hash/maphash under a seed fixed for the life of the
+// process.
+func MapHash(data []byte) uint32 {
+	var h maphash.Hash
+	h.SetSeed(mapHashSeed)
+	_, _ = h.Write(data)
+	return uint32(h.Sum64())
+}
@@ -0,0 +1,65 @@
+package hashers_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/davies/groupcache/consistenthash"
+	"github.com/davies/groupcache/consistenthash/hashers"
+)
+
+func TestHashersDeterministic(t *testing.T) {
+	data := []byte("some-key")
+	for name, fn := range map[string]consistenthash.Hash{
+		"XXHash":  hashers.XXHash,
+		"Murmur3": hashers.Murmur3,
+		"MapHash": hashers.MapHash,
+	} {
+		if fn(data) != fn(data) {
+			t.Errorf("%s is not deterministic for the same input", name)
+		}
+	}
+}
+
+func TestXXHashSeeded(t *testing.T) {
+	data := []byte("some-key")
+	a := hashers.XXHashSeeded(1)
+	b := hashers.XXHashSeeded(2)
+	if a(data) == b(data) {
+		t.Errorf("different seeds should (almost always) produce different hashes")
+	}
+	if a(data) != hashers.XXHashSeeded(1)(data) {
+		t.Errorf("same seed should produce the same hash")
+	}
+}
+
+func benchmarkGet(b *testing.B, fn consistenthash.Hash, shards int) {
+	hash := consistenthash.New(3000, fn)
+
+	var buckets []string
+	for i := 0; i < shards; i++ {
+		buckets = append(buckets, fmt.Sprintf("shard-%d", i))
+	}
+	hash.Add(buckets...)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		hash.Get(buckets[i&(shards-1)])
+	}
+}
+
+func BenchmarkXXHash8(b *testing.B)   { benchmarkGet(b, hashers.XXHash, 8) }
+func BenchmarkXXHash32(b *testing.B)  { benchmarkGet(b, hashers.XXHash, 32) }
+func BenchmarkXXHash128(b *testing.B) { benchmarkGet(b, hashers.XXHash, 128) }
+func BenchmarkXXHash512(b *testing.B) { benchmarkGet(b, hashers.XXHash, 512) }
+
+func BenchmarkMurmur38(b *testing.B)   { benchmarkGet(b, hashers.Murmur3, 8) }
+func BenchmarkMurmur332(b *testing.B)  { benchmarkGet(b, hashers.Murmur3, 32) }
+func BenchmarkMurmur3128(b *testing.B) { benchmarkGet(b, hashers.Murmur3, 128) }
+func BenchmarkMurmur3512(b *testing.B) { benchmarkGet(b, hashers.Murmur3, 512) }
+
+func BenchmarkMapHash8(b *testing.B)   { benchmarkGet(b, hashers.MapHash, 8) }
+func BenchmarkMapHash32(b *testing.B)  { benchmarkGet(b, hashers.MapHash, 32) }
+func BenchmarkMapHash128(b *testing.B) { benchmarkGet(b, hashers.MapHash, 128) }
+func BenchmarkMapHash512(b *testing.B) { benchmarkGet(b, hashers.MapHash, 512) }
@@ -0,0 +1,135 @@
+package consistenthash
+
+import "math"
+
+// RingEventType identifies what changed in a RingEvent.
+type RingEventType int
+
+const (
+	// RingEventAdd fires after AddWithWeight adds or reweights a node.
+	RingEventAdd RingEventType = iota
+	// RingEventRemove fires after Remove drops a node.
+	RingEventRemove
+	// RingEventAdjustIteration fires after each adjust() pass that changed
+	// replica counts, letting operators detect weight combinations that
+	// fail to converge within adjust's try budget.
+	RingEventAdjustIteration
+)
+
+// RingEvent describes a ring mutation, with the node's distribution just
+// before and just after it.
+type RingEvent struct {
+	Type      RingEventType
+	Node      string // the node added/removed; empty for adjust iterations
+	Iteration int    // the adjust() try number; zero for add/remove events
+	Before    map[string]float64
+	After     map[string]float64
+}
+
+// SetObserver registers fn to be called on every Add or Remove that changes
+// the node set or a weight, and on every adjust() iteration that changes
+// replica counts. Pass nil to stop observing. fn is called synchronously from
+// whichever goroutine triggered the mutation, while the writer lock is
+// held, so it must not call back into m.
+func (m *Map) SetObserver(fn func(RingEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observer = fn
+}
+
+func (m *Map) notify(typ RingEventType, node string, before, after map[string]float64) {
+	if m.observer == nil {
+		return
+	}
+	m.observer(RingEvent{Type: typ, Node: node, Before: before, After: after})
+}
+
+// observedDistribution returns state's Distribution, or nil if there is no
+// observer to report it to - callers don't want to pay for it otherwise.
+func (m *Map) observedDistribution(state *ringState) map[string]float64 {
+	if m.observer == nil {
+		return nil
+	}
+	if m.rendezvous {
+		return weightDistribution(state.replicas)
+	}
+	return distributionOf(state)
+}
+
+// arcCoverage returns, for state's ring, how much of the 32-bit key space
+// each node owns, in raw ring units (out of 1<<32).
+func arcCoverage(state *ringState) map[string]int {
+	stat := make(map[string]int, len(state.replicas))
+	if len(state.keys) == 0 {
+		return stat
+	}
+	stat[state.hashMap[state.keys[0]]] = state.keys[0] + int(1<<32) - state.keys[len(state.keys)-1]
+	for i, h := range state.keys[1:] {
+		stat[state.hashMap[h]] += h - state.keys[i]
+	}
+	return stat
+}
+
+func distributionOf(state *ringState) map[string]float64 {
+	if len(state.keys) == 0 {
+		return map[string]float64{}
+	}
+	stat := arcCoverage(state)
+	dist := make(map[string]float64, len(state.replicas))
+	for k := range state.replicas {
+		dist[k] = 0
+	}
+	for k, v := range stat {
+		dist[k] = float64(v) / float64(1<<32)
+	}
+	return dist
+}
+
+func weightDistribution(weights map[string]int) map[string]float64 {
+	var total int
+	for _, w := range weights {
+		total += w
+	}
+	dist := make(map[string]float64, len(weights))
+	if total == 0 {
+		return dist
+	}
+	for k, w := range weights {
+		dist[k] = float64(w) / float64(total)
+	}
+	return dist
+}
+
+// Distribution returns, for each node, the fraction of the 32-bit ring it
+// owns. In rendezvous mode there is no ring to measure, so it returns each
+// node's expected share: weight / total weight. It is a lock-free read.
+func (m *Map) Distribution() map[string]float64 {
+	state := m.state.Load()
+	if m.rendezvous {
+		return weightDistribution(state.replicas)
+	}
+	return distributionOf(state)
+}
+
+// Imbalance returns the ratio between the most- and least-loaded node's
+// share of Distribution. A value near 1 is well balanced; it is +Inf if any
+// registered node owns no share at all. It is a lock-free read.
+func (m *Map) Imbalance() float64 {
+	dist := m.Distribution()
+	if len(dist) == 0 {
+		return 0
+	}
+	min, max := math.Inf(1), 0.0
+	for _, v := range dist {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	if min == 0 {
+		return math.Inf(1)
+	}
+	return max / min
+}
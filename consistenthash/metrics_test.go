@@ -0,0 +1,67 @@
+package consistenthash
+
+import (
+	"testing"
+
+	"github.com/spaolacci/murmur3"
+)
+
+func TestDistributionSumsToOne(t *testing.T) {
+	hash := New(100, murmur3.Sum32)
+	hash.Add("a", "b", "c", "d")
+
+	var total float64
+	for _, frac := range hash.Distribution() {
+		total += frac
+	}
+	if total < 0.999 || total > 1.001 {
+		t.Errorf("distribution should sum to ~1, got %f", total)
+	}
+}
+
+func TestImbalanceEvenWeights(t *testing.T) {
+	hash := New(100, murmur3.Sum32)
+	for i := 0; i < 10; i++ {
+		hash.AddWithWeight(string(rune('a'+i)), 100)
+	}
+	if imb := hash.Imbalance(); imb > 2 {
+		t.Errorf("expected roughly balanced ring for equal weights, got imbalance %f", imb)
+	}
+}
+
+func TestRendezvousDistributionMatchesWeights(t *testing.T) {
+	hash := NewRendezvous(nil)
+	hash.AddWithWeight("heavy", 3)
+	hash.AddWithWeight("light", 1)
+
+	dist := hash.Distribution()
+	if got := dist["heavy"]; got < 0.74 || got > 0.76 {
+		t.Errorf("expected heavy node's share to be 0.75, got %f", got)
+	}
+}
+
+func TestSetObserverFiresOnAddAndRemove(t *testing.T) {
+	hash := New(3, nil)
+
+	var events []RingEvent
+	hash.SetObserver(func(e RingEvent) {
+		events = append(events, e)
+	})
+
+	hash.Add("a")
+	hash.Add("b")
+	hash.Remove("a")
+
+	var sawAdd, sawRemove bool
+	for _, e := range events {
+		switch e.Type {
+		case RingEventAdd:
+			sawAdd = true
+		case RingEventRemove:
+			sawRemove = true
+		}
+	}
+	if !sawAdd || !sawRemove {
+		t.Errorf("expected both add and remove events, got %+v", events)
+	}
+}
@@ -0,0 +1,104 @@
+package consistenthash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// snapshotVersion is bumped whenever the encoded snapshot layout changes.
+const snapshotVersion byte = 1
+
+// snapshotCheckInput is hashed at Snapshot and Restore time so a mismatched
+// Hash function between producer and consumer is caught instead of silently
+// producing a ring with different key ownership.
+const snapshotCheckInput = "consistenthash-snapshot-check"
+
+type snapshotBody struct {
+	Replica          int
+	Replicas         map[string]int
+	AdjustedReplicas map[string]int
+	Hashs            map[string][]int
+	HashCheck        uint32
+}
+
+// Snapshot serializes the ring: the requested replica counts, the replica
+// counts adjust() actually converged on, and the per-key hash cache in
+// m.hashs. Restore rebuilds the ring straight from this data, skipping the
+// O(tries*N) adjust() pass, so a restarted peer - or a fleet of peers all
+// loading the same snapshot - gets byte-identical key ownership.
+//
+// Snapshot only supports ring-mode Maps; it returns an error for a Map
+// created with NewRendezvous, which has no ring to capture.
+func (m *Map) Snapshot() ([]byte, error) {
+	if m.rendezvous {
+		return nil, errors.New("consistenthash: cannot snapshot a rendezvous Map")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := m.state.Load()
+	adjusted := make(map[string]int, len(state.replicas))
+	for _, h := range state.keys {
+		adjusted[state.hashMap[h]]++
+	}
+
+	body := snapshotBody{
+		Replica:          m.replica,
+		Replicas:         copyWeights(m.replicas),
+		AdjustedReplicas: adjusted,
+		Hashs:            m.hashs,
+		HashCheck:        m.hash([]byte(snapshotCheckInput)),
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&body); err != nil {
+		return nil, err
+	}
+	encoded := buf.Bytes()
+
+	out := make([]byte, 0, 1+4+len(encoded))
+	out = append(out, snapshotVersion)
+	out = binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(encoded))
+	out = append(out, encoded...)
+	return out, nil
+}
+
+// Restore rebuilds a Map from data produced by Snapshot, hashing with fn. It
+// returns an error if data is corrupt, was written by an incompatible
+// snapshot version, or was produced with a different Hash function than fn
+// (detected via a checksum of a fixed check input, since the two functions
+// would otherwise silently disagree about key ownership).
+func Restore(data []byte, fn Hash) (*Map, error) {
+	if len(data) < 5 {
+		return nil, errors.New("consistenthash: snapshot too short")
+	}
+	if data[0] != snapshotVersion {
+		return nil, fmt.Errorf("consistenthash: unsupported snapshot version %d", data[0])
+	}
+	wantSum := binary.BigEndian.Uint32(data[1:5])
+	encoded := data[5:]
+	if crc32.ChecksumIEEE(encoded) != wantSum {
+		return nil, errors.New("consistenthash: snapshot checksum mismatch")
+	}
+
+	var body snapshotBody
+	if err := gob.NewDecoder(bytes.NewReader(encoded)).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	m := New(body.Replica, fn)
+	if m.hash([]byte(snapshotCheckInput)) != body.HashCheck {
+		return nil, errors.New("consistenthash: snapshot was produced with a different Hash function")
+	}
+
+	m.replicas = body.Replicas
+	m.hashs = body.Hashs
+	keys, hashMap := m.calcRing(body.AdjustedReplicas)
+	m.state.Store(&ringState{keys: keys, hashMap: hashMap, replicas: copyWeights(body.Replicas)})
+	return m, nil
+}
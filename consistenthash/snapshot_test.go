@@ -0,0 +1,66 @@
+package consistenthash
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spaolacci/murmur3"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	hash := New(100, murmur3.Sum32)
+	hash.Add("a", "b", "c", "d")
+
+	want := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		want[key] = hash.Get(key)
+	}
+
+	data, err := hash.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	restored, err := Restore(data, murmur3.Sum32)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	for key, node := range want {
+		if got := restored.Get(key); got != node {
+			t.Errorf("restored map disagrees on %s: got %s, want %s", key, got, node)
+		}
+	}
+}
+
+func TestRestoreWrongHashFunc(t *testing.T) {
+	hash := New(100, murmur3.Sum32)
+	hash.Add("a", "b", "c")
+
+	data, err := hash.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	if _, err := Restore(data, polyHash31); err == nil {
+		t.Errorf("expected Restore to reject a snapshot produced with a different Hash function")
+	}
+}
+
+func polyHash31(data []byte) uint32 {
+	var h uint32
+	for _, b := range data {
+		h = h*31 + uint32(b)
+	}
+	return h
+}
+
+func TestSnapshotRendezvousUnsupported(t *testing.T) {
+	hash := NewRendezvous(nil)
+	hash.Add("a", "b")
+
+	if _, err := hash.Snapshot(); err == nil {
+		t.Errorf("expected Snapshot to reject a rendezvous Map")
+	}
+}